@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mholt/archives"
+)
+
+// defaultMaxSize bounds both per-file and total decompressed archive size
+// when the corresponding Args field is left unset, guarding against
+// zip-bomb style denial of service during npm install.
+const defaultMaxSize = 2 << 30 // 2 GiB
+
+// extractAllTo extracts every entry in archiveFile into destination. The
+// archive format is detected from the file's name/contents via
+// archives.Identify, so zip, tar, tar.gz, tar.xz, and tar.bz2 releases are
+// all handled the same way. Entries are confined to destination (rejecting
+// zip-slip paths and symlinks that would escape it) and capped in size.
+func extractAllTo(archiveFile *os.File, destination string, args Args) error {
+	ctx := context.Background()
+
+	format, stream, err := archives.Identify(ctx, filepath.Base(archiveFile.Name()), archiveFile)
+	if err != nil {
+		return fmt.Errorf("failed to identify archive format for %s: %w", archiveFile.Name(), err)
+	}
+
+	extractor, ok := format.(archives.Extractor)
+	if !ok {
+		return fmt.Errorf("archive format for %s does not support extraction", archiveFile.Name())
+	}
+
+	destRoot, err := filepath.EvalSymlinks(destination)
+	if err != nil {
+		return err
+	}
+
+	maxFileSize := args.MaxFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxSize
+	}
+
+	maxArchiveSize := args.MaxArchiveSize
+	if maxArchiveSize <= 0 {
+		maxArchiveSize = defaultMaxSize
+	}
+
+	var totalWritten int64
+
+	return extractor.Extract(ctx, stream, func(ctx context.Context, info archives.FileInfo) error {
+		outPath, err := safeJoin(destRoot, info.NameInArchive)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return os.MkdirAll(outPath, 0755)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			return err
+		}
+
+		if info.LinkTarget != "" {
+			return writeSymlink(destRoot, outPath, info.LinkTarget)
+		}
+
+		out, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		in, err := info.Open()
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		written, err := io.Copy(out, io.LimitReader(in, maxFileSize+1))
+		if err != nil {
+			return err
+		}
+
+		if written > maxFileSize {
+			return fmt.Errorf("%s exceeds the maximum allowed file size of %d bytes", info.NameInArchive, maxFileSize)
+		}
+
+		totalWritten += written
+		if totalWritten > maxArchiveSize {
+			return fmt.Errorf("archive exceeds the maximum allowed total size of %d bytes", maxArchiveSize)
+		}
+
+		return nil
+	})
+}
+
+// safeJoin joins name onto destRoot, rejecting absolute paths and any entry
+// whose cleaned path would resolve outside destRoot (zip-slip).
+func safeJoin(destRoot, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("archive entry has an absolute path: %s", name)
+	}
+
+	cleaned := filepath.Clean(filepath.Join(destRoot, name))
+
+	rel, err := filepath.Rel(destRoot, cleaned)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry escapes destination: %s", name)
+	}
+
+	return cleaned, nil
+}
+
+// writeSymlink creates a symlink at outPath, rejecting link targets that
+// would resolve outside destRoot.
+func writeSymlink(destRoot, outPath, linkTarget string) error {
+	target := linkTarget
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(outPath), target)
+	}
+
+	rel, err := filepath.Rel(destRoot, filepath.Clean(target))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("symlink target escapes destination: %s -> %s", outPath, linkTarget)
+	}
+
+	os.Remove(outPath)
+	return os.Symlink(linkTarget, outPath)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafeJoin(t *testing.T) {
+	destRoot := "/dest"
+
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{name: "normal nested entry", entry: "dict/jp/dic.mecab"},
+		{name: "absolute path", entry: "/etc/passwd", wantErr: true},
+		{name: "simple zip-slip", entry: "../../etc/passwd", wantErr: true},
+		{name: "zip-slip disguised with a leading normal segment", entry: "dict/../../etc/passwd", wantErr: true},
+		{name: "entry equal to destRoot", entry: ".", wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := safeJoin(destRoot, tt.entry)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got path %q", out)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rel, err := filepath.Rel(destRoot, out)
+			if err != nil || rel == ".." || filepath.IsAbs(rel) {
+				t.Fatalf("resolved path %q escapes destRoot %q", out, destRoot)
+			}
+		})
+	}
+}
+
+func TestWriteSymlink(t *testing.T) {
+	destRoot := t.TempDir()
+
+	tests := []struct {
+		name       string
+		outPath    string
+		linkTarget string
+		wantErr    bool
+	}{
+		{
+			name:       "relative target inside destRoot",
+			outPath:    filepath.Join(destRoot, "link"),
+			linkTarget: "real",
+		},
+		{
+			name:       "relative target escaping destRoot",
+			outPath:    filepath.Join(destRoot, "link"),
+			linkTarget: "../../etc/passwd",
+			wantErr:    true,
+		},
+		{
+			name:       "absolute target outside destRoot",
+			outPath:    filepath.Join(destRoot, "link"),
+			linkTarget: "/etc/passwd",
+			wantErr:    true,
+		},
+		{
+			name:       "absolute target inside destRoot",
+			outPath:    filepath.Join(destRoot, "link"),
+			linkTarget: filepath.Join(destRoot, "real"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(tt.outPath)
+
+			err := writeSymlink(destRoot, tt.outPath, tt.linkTarget)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error for a symlink target escaping destRoot")
+				}
+
+				if _, statErr := os.Lstat(tt.outPath); statErr == nil {
+					t.Fatal("rejected symlink should not have been created")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			target, err := os.Readlink(tt.outPath)
+			if err != nil {
+				t.Fatalf("failed to read created symlink: %v", err)
+			}
+
+			if target != tt.linkTarget {
+				t.Fatalf("got link target %q, want %q", target, tt.linkTarget)
+			}
+		})
+	}
+}
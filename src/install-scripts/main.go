@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,14 +8,23 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alexflint/go-arg"
-	"github.com/mholt/archives"
 )
 
 type Args struct {
-	Lang []string `arg:"-l,--lang" help:"Comma-delimited language codes for which engine/dictionaries to install. Currently supported are jp and ko. If omitted, downloads all languages."`
+	Lang      []string `arg:"-l,--lang" help:"Comma-delimited language codes for which engine/dictionaries to install. Currently supported are jp and ko. If omitted, downloads all languages."`
+	NoVerify  bool     `arg:"--no-verify" help:"Skip SHA-256 checksum verification of downloaded release assets."`
+	Signature bool     `arg:"--signature" help:"Additionally verify a detached PGP signature for each downloaded asset."`
+	Retries   int      `arg:"--retries" default:"3" help:"Number of retries for transient GitHub API/network failures."`
+
+	MaxFileSize    int64  `arg:"--max-file-size" default:"2147483648" help:"Maximum allowed decompressed size in bytes for any single file in an archive."`
+	MaxArchiveSize int64  `arg:"--max-archive-size" default:"2147483648" help:"Maximum allowed total decompressed size in bytes for an archive."`
+	FromDir        string `arg:"--from-dir" help:"Install from a local directory of pre-staged asset archives instead of fetching from GitHub. Also settable via NAPI_MECAB_OFFLINE_DIR."`
+	NoProgress     bool   `arg:"--no-progress" help:"Disable the download progress bar/NDJSON output."`
 }
 
 var supported = map[string]bool{
@@ -39,7 +47,7 @@ func main() {
 			lower := strings.ToLower(lang)
 			if !supported[lower] {
 				fmt.Printf("'%s' is not a supported language code\n", lang)
-				return
+				os.Exit(1)
 			}
 
 			requested = append(requested, lower)
@@ -50,7 +58,7 @@ func main() {
 	exe, err := os.Executable()
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 
 	exeDir := filepath.Dir(exe)
@@ -60,97 +68,124 @@ func main() {
 	version, err := getPkgVersion(pkgPath)
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 
-	release, err := getGithubRelease(version)
-	if err != nil {
-		fmt.Println(err)
-		return
+	fromDir := args.FromDir
+	if fromDir == "" {
+		fromDir = os.Getenv("NAPI_MECAB_OFFLINE_DIR")
 	}
 
-	// setup temporary directory for archive downloads
-	tempDir, err := os.MkdirTemp("", "mecab")
-	if err != nil {
+	var source AssetSource
+
+	if fromDir != "" {
+		if args.Signature {
+			fmt.Println("--signature requires fetching from GitHub; it isn't supported with --from-dir")
+			os.Exit(1)
+		}
+
+		source = newLocalAssetSource(fromDir, "v"+version)
+	} else {
+		release, err := getGithubRelease(version, args)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		source = newGithubAssetSource(release, args)
+	}
+
+	// downloadCacheDir is a stable, caller-controlled location (unlike
+	// os.MkdirTemp, which picks a fresh random path every run) so that a
+	// partially-downloaded archive surviving a hard kill can actually be
+	// found and resumed by a later invocation. It's only removed once the
+	// whole install has succeeded.
+	downloadCacheDir := filepath.Join(exeDir, ".download-cache")
+	if err := os.MkdirAll(downloadCacheDir, 0755); err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
-	defer os.RemoveAll(tempDir)
 
 	// download engine into appropriate runtime prebuild directory
-	prebuildURL, err := release.getBinaryURL()
+	prebuildName, engineArchivePath, err := source.FetchBinary(downloadCacheDir)
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 
-	engineZipPath := filepath.Join(tempDir, "engine.zip")
-	err = downloadAsset(prebuildURL, engineZipPath)
+	err = verifyDownloadedAsset(source, prebuildName, engineArchivePath, args)
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 
-	engineZip, err := os.Open(engineZipPath)
+	engineArchive, err := os.Open(engineArchivePath)
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
-	defer engineZip.Close()
+	defer engineArchive.Close()
 
 	prebuildDir := filepath.Join(exeDir, "..", "prebuilds")
 	os.RemoveAll(prebuildDir)
 	err = os.MkdirAll(prebuildDir, 0755)
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 
-	err = extractAllTo(engineZip, prebuildDir)
+	err = extractAllTo(engineArchive, prebuildDir, args)
 	if err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
+	engineArchive.Close()
+	os.Remove(engineArchivePath)
 
 	rt := getRuntime()
 
 	// download requested language dictionaries into /dict
 	for _, lang := range requested {
-		dictURL, err := release.getDictionaryURL(lang)
+		dictName, dictArchivePath, err := source.FetchDictionary(lang, downloadCacheDir)
 		if err != nil {
 			fmt.Println(err)
-			return
+			os.Exit(1)
 		}
 
-		dictZipPath := filepath.Join(tempDir, fmt.Sprintf("%s.zip", lang))
-		err = downloadAsset(dictURL, dictZipPath)
+		err = verifyDownloadedAsset(source, dictName, dictArchivePath, args)
 		if err != nil {
 			fmt.Println(err)
-			return
+			os.Exit(1)
 		}
 
-		dictZip, err := os.Open(dictZipPath)
+		dictArchive, err := os.Open(dictArchivePath)
 		if err != nil {
 			fmt.Println(err)
-			return
+			os.Exit(1)
 		}
-		defer dictZip.Close()
+		defer dictArchive.Close()
 
 		dictDir := filepath.Join(exeDir, "..", "dict", lang)
 		os.RemoveAll(dictDir)
 		err = os.MkdirAll(dictDir, 0755)
 		if err != nil {
 			fmt.Println(err)
-			return
+			os.Exit(1)
 		}
 
-		err = extractAllTo(dictZip, dictDir)
+		err = extractAllTo(dictArchive, dictDir, args)
 		if err != nil {
 			fmt.Println(err)
-			return
+			os.Exit(1)
 		}
+		dictArchive.Close()
+		os.Remove(dictArchivePath)
 	}
 
+	// every archive was removed above as it was successfully extracted, so
+	// this only cleans up the now-empty cache directory itself
+	os.Remove(downloadCacheDir)
+
 	fmt.Printf("Successfully installed prebuilt binary for your %s system and dictionaries for %s\n", rt, strings.Join(requested, ", "))
 }
 
@@ -164,13 +199,25 @@ type GithubAsset struct {
 	URL  string `json:"url"`
 }
 
-func getGithubRelease(version string) (*GithubRelease, error) {
-	res, err := http.Get(fmt.Sprintf("https://api.github.com/repos/jacoblockett/napi-mecab/releases/tags/v%s", version))
+func getGithubRelease(version string, args Args) (*GithubRelease, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/jacoblockett/napi-mecab/releases/tags/v%s", version), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "napi-mecab-installer")
+	applyGithubAuth(req)
+
+	res, err := doWithRetries(http.DefaultClient, req, args.Retries)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 
+	if err := checkRateLimit(res); err != nil {
+		return nil, err
+	}
+
 	if res.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("failed to fetch release info, bad status: %s", res.Status)
 	}
@@ -189,28 +236,99 @@ func getGithubRelease(version string) (*GithubRelease, error) {
 	return &release, nil
 }
 
-func (r *GithubRelease) getBinaryURL() (string, error) {
-	name := fmt.Sprintf("prebuilds-%s-%s-%s.zip", runtime.GOOS, r.Version, getArch())
+// applyGithubAuth attaches GITHUB_TOKEN (and, if also set, GITHUB_USER) to
+// req so requests count against an authenticated rate limit instead of the
+// unauthenticated 60/hour-per-IP cap.
+func applyGithubAuth(req *http.Request) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return
+	}
+
+	if user := os.Getenv("GITHUB_USER"); user != "" {
+		req.SetBasicAuth(user, token)
+		return
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+// checkRateLimit turns a GitHub API rate-limit response into a clear error
+// instead of letting it fall through as a generic bad-status message.
+func checkRateLimit(res *http.Response) error {
+	if res.StatusCode != http.StatusForbidden || res.Header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+
+	epoch, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("rate-limited by the GitHub API")
+	}
+
+	return fmt.Errorf("rate-limited by the GitHub API until %s", time.Unix(epoch, 0).UTC().Format(time.RFC3339))
+}
+
+// doWithRetries executes req, retrying with exponential backoff on
+// connection errors and 5xx responses so a transient GitHub outage doesn't
+// abort a full install halfway through.
+func doWithRetries(client *http.Client, req *http.Request, retries int) (*http.Response, error) {
+	var lastErr error
 
-	for _, asset := range r.Assets {
-		if asset.Name == name {
-			return asset.URL, nil
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<uint(attempt-1)) * time.Second)
 		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if res.StatusCode >= 500 && attempt < retries {
+			res.Body.Close()
+			lastErr = fmt.Errorf("bad status: %s", res.Status)
+			continue
+		}
+
+		return res, nil
 	}
 
-	return "", fmt.Errorf("couldn't find a prebuild for %s", name)
+	return nil, fmt.Errorf("request to %s failed after %d attempts: %w", req.URL, retries+1, lastErr)
 }
 
-func (r *GithubRelease) getDictionaryURL(lang string) (string, error) {
-	name := fmt.Sprintf("%s.zip", lang)
+// archiveExtPreference orders the archive formats the installer will accept
+// for a given base asset name, favoring the smaller tar-based formats and
+// falling back to zip for engine builds that are only published for
+// Windows.
+var archiveExtPreference = []string{".tar.xz", ".tar.gz", ".zip"}
+
+func (r *GithubRelease) getBinaryURL() (string, string, error) {
+	base := fmt.Sprintf("prebuilds-%s-%s-%s", runtime.GOOS, r.Version, getArch())
+
+	for _, ext := range archiveExtPreference {
+		name := base + ext
+		for _, asset := range r.Assets {
+			if asset.Name == name {
+				return asset.URL, asset.Name, nil
+			}
+		}
+	}
 
-	for _, asset := range r.Assets {
-		if asset.Name == name {
-			return asset.URL, nil
+	return "", "", fmt.Errorf("couldn't find a prebuild for %s (tried %s)", base, strings.Join(archiveExtPreference, ", "))
+}
+
+func (r *GithubRelease) getDictionaryURL(lang string) (string, string, error) {
+	for _, ext := range archiveExtPreference {
+		name := lang + ext
+		for _, asset := range r.Assets {
+			if asset.Name == name {
+				return asset.URL, asset.Name, nil
+			}
 		}
 	}
 
-	return "", fmt.Errorf("couldn't find a dictionary for %s", name)
+	return "", "", fmt.Errorf("couldn't find a dictionary for %s (tried %s)", lang, strings.Join(archiveExtPreference, ", "))
 }
 
 func getRuntime() string {
@@ -252,67 +370,117 @@ func getPkgVersion(path string) (string, error) {
 	return pkg.Version, nil
 }
 
-func downloadAsset(url, destination string) error {
+// downloadAsset fetches url into destination, retrying transient failures
+// with exponential backoff. Unlike doWithRetries, the retry loop here spans
+// the whole transfer, not just establishing the response: a connection
+// reset partway through a large dictionary archive resumes from wherever
+// the prior attempt left off via attemptDownload's Range request, rather
+// than aborting the install outright.
+func downloadAsset(url, destination string, args Args) error {
+	var lastErr error
+
+	for attempt := 0; attempt <= args.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<uint(attempt-1)) * time.Second)
+		}
+
+		done, err := attemptDownload(url, destination, args)
+		if done {
+			return err
+		}
+
+		lastErr = err
+	}
+
+	return fmt.Errorf("download of %s failed after %d attempts: %w", url, args.Retries+1, lastErr)
+}
+
+// attemptDownload performs a single download attempt, resuming from any
+// partial file already at destination via a Range request. It reports
+// done=true for terminal outcomes (success, or an error retrying can't fix,
+// such as a GitHub rate limit) and done=false for transient failures that
+// downloadAsset should retry.
+func attemptDownload(url, destination string, args Args) (done bool, err error) {
+	var resumeFrom int64
+	if info, err := os.Stat(destination); err == nil {
+		resumeFrom = info.Size()
+	}
+
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return true, err
 	}
 
 	req.Header.Set("Accept", "application/octet-stream")
 	req.Header.Set("User-Agent", "napi-mecab-installer")
+	applyGithubAuth(req)
+
+	if resumeFrom > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeFrom))
+	}
 
 	client := &http.Client{
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			req.Header.Set("Accept", "application/octet-stream")
 			req.Header.Set("User-Agent", "napi-mecab-installer")
+
+			// GitHub redirects asset downloads to a presigned CDN URL on a
+			// different host; net/http already strips Authorization on
+			// cross-host redirects, and re-adding it here would both leak
+			// GITHUB_TOKEN to that host and collide with the CDN's own
+			// query-string signature. Only reapply auth when the redirect
+			// stays on the original host.
+			if req.URL.Host == via[0].URL.Host {
+				applyGithubAuth(req)
+			}
+
 			return nil
 		},
 	}
 
 	res, err := client.Do(req)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("%s bad status: %s", url, res.Status)
-	}
-
-	out, err := os.Create(destination)
-	if err != nil {
-		return err
+	if err := checkRateLimit(res); err != nil {
+		return true, err
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, res.Body)
-	return err
-}
-
-func extractAllTo(zipFile *os.File, destination string) error {
-	var format archives.Zip
 
-	return format.Extract(context.Background(), zipFile, func(ctx context.Context, info archives.FileInfo) error {
-		outPath := filepath.Join(destination, info.NameInArchive)
+	var out *os.File
+	var alreadyDownloaded, total int64
 
-		if info.IsDir() {
-			return os.MkdirAll(outPath, 0755)
-		}
-
-		os.MkdirAll(filepath.Dir(outPath), 0755)
-		out, err := os.Create(outPath)
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		out, err = os.OpenFile(destination, os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
-			return err
+			return true, err
 		}
-		defer out.Close()
-
-		in, err := info.Open()
+		alreadyDownloaded = resumeFrom
+		total = resumeFrom + res.ContentLength
+	case http.StatusOK:
+		out, err = os.Create(destination)
 		if err != nil {
-			return err
+			return true, err
 		}
-		defer in.Close()
+		total = res.ContentLength
+	default:
+		return res.StatusCode < 500, fmt.Errorf("%s bad status: %s", url, res.Status)
+	}
+	defer out.Close()
+
+	var body io.Reader = res.Body
+
+	if !args.NoProgress {
+		reporter := newProgressReporter(total, alreadyDownloaded)
+		body = &progressReader{Reader: res.Body, reporter: reporter}
+		defer reporter.finish()
+	}
+
+	if _, err := io.Copy(out, body); err != nil {
+		return false, err
+	}
 
-		_, err = io.Copy(out, in)
-		return err
-	})
+	return true, nil
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progressReporter renders download progress to stdout: a redrawing
+// terminal bar (bytes downloaded, total, throughput, ETA) when stdout is a
+// TTY, or one NDJSON line per second otherwise so `npm install` logs stay
+// parseable in CI.
+type progressReporter struct {
+	total      int64
+	downloaded int64
+	start      time.Time
+	lastEmit   time.Time
+	isTTY      bool
+}
+
+func newProgressReporter(total, alreadyDownloaded int64) *progressReporter {
+	if total < 0 {
+		total = 0
+	}
+
+	return &progressReporter{
+		total:      total,
+		downloaded: alreadyDownloaded,
+		start:      time.Now(),
+		isTTY:      term.IsTerminal(int(os.Stdout.Fd())),
+	}
+}
+
+func (p *progressReporter) add(n int64) {
+	p.downloaded += n
+
+	if time.Since(p.lastEmit) < time.Second && (p.total <= 0 || p.downloaded < p.total) {
+		return
+	}
+
+	p.lastEmit = time.Now()
+	p.render()
+}
+
+func (p *progressReporter) render() {
+	elapsed := time.Since(p.start).Seconds()
+
+	var throughput float64
+	if elapsed > 0 {
+		throughput = float64(p.downloaded) / elapsed
+	}
+
+	if p.isTTY {
+		p.renderBar(throughput)
+		return
+	}
+
+	p.renderNDJSON(throughput)
+}
+
+func (p *progressReporter) renderBar(throughput float64) {
+	const width = 30
+
+	var pct float64
+	if p.total > 0 {
+		pct = float64(p.downloaded) / float64(p.total)
+	}
+
+	filled := int(pct * width)
+	if filled > width {
+		filled = width
+	}
+
+	bar := make([]byte, width)
+	for i := range bar {
+		if i < filled {
+			bar[i] = '='
+		} else {
+			bar[i] = ' '
+		}
+	}
+
+	eta := "?"
+	if throughput > 0 && p.total > p.downloaded {
+		remaining := time.Duration(float64(p.total-p.downloaded) / throughput * float64(time.Second))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	fmt.Printf("\r[%s] %s/%s  %s/s  ETA %s", bar, formatBytes(p.downloaded), formatBytes(p.total), formatBytes(int64(throughput)), eta)
+}
+
+func (p *progressReporter) renderNDJSON(throughput float64) {
+	line, err := json.Marshal(struct {
+		BytesDownloaded int64 `json:"bytesDownloaded"`
+		TotalBytes      int64 `json:"totalBytes"`
+		ThroughputBps   int64 `json:"throughputBps"`
+	}{p.downloaded, p.total, int64(throughput)})
+	if err != nil {
+		return
+	}
+
+	fmt.Println(string(line))
+}
+
+// finish prints a trailing newline after a redrawing terminal bar so
+// subsequent output doesn't overwrite the last progress line.
+func (p *progressReporter) finish() {
+	if p.isTTY {
+		fmt.Println()
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// progressReader wraps an io.Reader and reports bytes read to a
+// progressReporter as they're consumed.
+type progressReader struct {
+	io.Reader
+	reporter *progressReporter
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.reporter.add(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// AssetSource abstracts where installer archives come from, so the same
+// download/verify/extract pipeline in main works whether assets are fetched
+// live from a GitHub release or staged ahead of time on local disk.
+type AssetSource interface {
+	// FetchBinary places the engine archive for the current OS/arch into
+	// destDir and returns its logical asset name and the path it was
+	// written to.
+	FetchBinary(destDir string) (name, path string, err error)
+	// FetchDictionary places the dictionary archive for lang into destDir
+	// and returns its logical asset name and the path it was written to.
+	FetchDictionary(lang, destDir string) (name, path string, err error)
+	// Checksum returns the expected hex-encoded sha256 digest for assetName.
+	Checksum(assetName string) (string, error)
+}
+
+// githubAssetSource fetches archives from a GithubRelease over the network.
+type githubAssetSource struct {
+	release *GithubRelease
+	args    Args
+}
+
+func newGithubAssetSource(release *GithubRelease, args Args) *githubAssetSource {
+	return &githubAssetSource{release: release, args: args}
+}
+
+func (s *githubAssetSource) FetchBinary(destDir string) (string, string, error) {
+	url, name, err := s.release.getBinaryURL()
+	if err != nil {
+		return "", "", err
+	}
+
+	path := filepath.Join(destDir, name)
+	if err := downloadAsset(url, path, s.args); err != nil {
+		return "", "", err
+	}
+
+	return name, path, nil
+}
+
+func (s *githubAssetSource) FetchDictionary(lang, destDir string) (string, string, error) {
+	url, name, err := s.release.getDictionaryURL(lang)
+	if err != nil {
+		return "", "", err
+	}
+
+	path := filepath.Join(destDir, name)
+	if err := downloadAsset(url, path, s.args); err != nil {
+		return "", "", err
+	}
+
+	return name, path, nil
+}
+
+func (s *githubAssetSource) Checksum(assetName string) (string, error) {
+	checksumURL, err := s.release.getChecksumURL(assetName)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := fetchChecksumManifest(checksumURL, s.args)
+	if err != nil {
+		return "", err
+	}
+
+	return parseChecksumManifest(manifest, assetName)
+}
+
+// localAssetSource reads pre-staged archives from a directory on disk,
+// matching the same naming convention GitHub releases use. This lets
+// downstream packagers pre-fetch binaries once and vendor them, or ship an
+// "everything included" install for air-gapped/offline environments.
+type localAssetSource struct {
+	dir     string
+	version string
+}
+
+func newLocalAssetSource(dir, version string) *localAssetSource {
+	return &localAssetSource{dir: dir, version: version}
+}
+
+func (s *localAssetSource) FetchBinary(destDir string) (string, string, error) {
+	base := fmt.Sprintf("prebuilds-%s-%s-%s", runtime.GOOS, s.version, getArch())
+	return s.copyStaged(base, destDir)
+}
+
+func (s *localAssetSource) FetchDictionary(lang, destDir string) (string, string, error) {
+	return s.copyStaged(lang, destDir)
+}
+
+func (s *localAssetSource) copyStaged(base, destDir string) (string, string, error) {
+	for _, ext := range archiveExtPreference {
+		name := base + ext
+		src := filepath.Join(s.dir, name)
+
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+
+		dst := filepath.Join(destDir, name)
+		if err := copyFile(src, dst); err != nil {
+			return "", "", err
+		}
+
+		return name, dst, nil
+	}
+
+	return "", "", fmt.Errorf("couldn't find a staged asset for %s in %s (tried %s)", base, s.dir, strings.Join(archiveExtPreference, ", "))
+}
+
+func (s *localAssetSource) Checksum(assetName string) (string, error) {
+	if data, err := os.ReadFile(filepath.Join(s.dir, assetName+".sha256")); err == nil {
+		return parseChecksumManifest(data, assetName)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(s.dir, "SHASUMS256.txt"))
+	if err != nil {
+		return "", fmt.Errorf("couldn't find a checksum for %s in %s", assetName, s.dir)
+	}
+
+	return parseChecksumManifest(manifest, assetName)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+//go:embed trusted_key.asc
+var trustedPublicKey []byte
+
+// placeholderKeyMarker appears in trusted_key.asc until the maintainers
+// embed their actual release signing key. Checking for it up front turns a
+// confusing openpgp parse failure into an unambiguous "not configured"
+// error when a build ships without a real key.
+const placeholderKeyMarker = "REPLACE_WITH_ACTUAL_ARMORED_PUBLIC_KEY"
+
+// getChecksumURL looks up the GitHub asset URL for the ".sha256" sidecar
+// file matching assetName, falling back to a combined SHASUMS256.txt
+// manifest if no per-asset sidecar was published for this release.
+func (r *GithubRelease) getChecksumURL(assetName string) (string, error) {
+	sidecar := assetName + ".sha256"
+
+	for _, asset := range r.Assets {
+		if asset.Name == sidecar {
+			return asset.URL, nil
+		}
+	}
+
+	for _, asset := range r.Assets {
+		if asset.Name == "SHASUMS256.txt" {
+			return asset.URL, nil
+		}
+	}
+
+	return "", fmt.Errorf("couldn't find a checksum for %s", assetName)
+}
+
+// fetchChecksumManifest downloads the contents of a checksum sidecar or
+// manifest file over HTTPS, using the same headers and auth as
+// downloadAsset and retrying transient failures via doWithRetries.
+func fetchChecksumManifest(url string, args Args) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/octet-stream")
+	req.Header.Set("User-Agent", "napi-mecab-installer")
+	applyGithubAuth(req)
+
+	res, err := doWithRetries(http.DefaultClient, req, args.Retries)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if err := checkRateLimit(res); err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch checksum manifest, bad status: %s", res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// parseChecksumManifest extracts the expected hex digest for assetName out
+// of a "<hex>  <filename>" formatted manifest (the format sha256sum and
+// SHASUMS256.txt files use). A single-line manifest is treated as a bare
+// per-asset sidecar and its digest is returned unconditionally.
+func parseChecksumManifest(data []byte, assetName string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	if len(lines) == 1 {
+		if fields := strings.Fields(lines[0]); len(fields) >= 1 {
+			return fields[0], nil
+		}
+	}
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		if fields[1] == assetName || strings.TrimPrefix(fields[1], "*") == assetName {
+			return fields[0], nil
+		}
+	}
+
+	return "", fmt.Errorf("no checksum entry found for %s", assetName)
+}
+
+// verifyChecksum streams the file at path through sha256 and compares the
+// result against expected, a hex-encoded digest, in constant time.
+func verifyChecksum(path, expected string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+
+	if subtle.ConstantTimeCompare([]byte(actual), []byte(strings.ToLower(expected))) != 1 {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, expected, actual)
+	}
+
+	return nil
+}
+
+// verifyAsset enforces that a downloaded asset has a matching checksum,
+// as reported by source, before it's handed off for extraction. The
+// downloaded file is deleted on any verification failure so a caller can't
+// accidentally extract it afterward.
+func verifyAsset(source AssetSource, assetName, path string, args Args) error {
+	if args.NoVerify {
+		return nil
+	}
+
+	expected, err := source.Checksum(assetName)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("refusing to extract %s without a checksum (pass --no-verify to skip): %w", assetName, err)
+	}
+
+	if err := verifyChecksum(path, expected); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// verifyDownloadedAsset runs verifyAsset and, if --signature was passed,
+// also verifies a detached PGP signature. Signature verification is only
+// supported when fetching from GitHub, since a local --from-dir staging
+// area has no equivalent of a release's published assets.
+func verifyDownloadedAsset(source AssetSource, assetName, path string, args Args) error {
+	if err := verifyAsset(source, assetName, path, args); err != nil {
+		return err
+	}
+
+	if !args.Signature {
+		return nil
+	}
+
+	ghSource, ok := source.(*githubAssetSource)
+	if !ok {
+		os.Remove(path)
+		return fmt.Errorf("--signature requires fetching from GitHub; it isn't supported with --from-dir")
+	}
+
+	if err := verifySignature(ghSource.release, assetName, path, args); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// verifySignature verifies a detached PGP signature, published alongside
+// each asset as "<name>.sig", against the bundled trusted public key.
+func verifySignature(release *GithubRelease, assetName, path string, args Args) error {
+	if bytes.Contains(trustedPublicKey, []byte(placeholderKeyMarker)) {
+		return fmt.Errorf("signing key not configured: this build embeds a placeholder trusted_key.asc, so --signature can't be used")
+	}
+
+	sigName := assetName + ".sig"
+
+	var sigURL string
+	for _, asset := range release.Assets {
+		if asset.Name == sigName {
+			sigURL = asset.URL
+			break
+		}
+	}
+
+	if sigURL == "" {
+		return fmt.Errorf("couldn't find a signature for %s", assetName)
+	}
+
+	sig, err := fetchChecksumManifest(sigURL, args)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(trustedPublicKey))
+	if err != nil {
+		return fmt.Errorf("failed to load trusted public key: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := openpgp.CheckDetachedSignature(keyring, f, bytes.NewReader(sig)); err != nil {
+		return fmt.Errorf("signature verification failed for %s: %w", assetName, err)
+	}
+
+	return nil
+}
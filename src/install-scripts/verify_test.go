@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksumManifest(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifest  string
+		assetName string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "bare single-line sidecar",
+			manifest:  "abc123\n",
+			assetName: "prebuilds-linux-v1.0.0-x64.tar.gz",
+			want:      "abc123",
+		},
+		{
+			name:      "multi-line manifest, text mode entry",
+			manifest:  "abc123  jp.zip\ndef456  ko.zip\n",
+			assetName: "ko.zip",
+			want:      "def456",
+		},
+		{
+			name:      "multi-line manifest, binary mode entry with * prefix",
+			manifest:  "abc123  jp.zip\ndef456 *ko.zip\n",
+			assetName: "ko.zip",
+			want:      "def456",
+		},
+		{
+			name:      "multi-line manifest, no matching entry",
+			manifest:  "abc123  jp.zip\ndef456  ko.zip\n",
+			assetName: "en.zip",
+			wantErr:   true,
+		},
+		{
+			name:      "empty manifest",
+			manifest:  "",
+			assetName: "jp.zip",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChecksumManifest([]byte(tt.manifest), tt.assetName)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got digest %q", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "asset.bin")
+
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	// sha256("hello world")
+	const expected = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	if err := verifyChecksum(path, expected); err != nil {
+		t.Fatalf("expected matching checksum to verify, got: %v", err)
+	}
+
+	mismatched := strings.Repeat("0", len(expected))
+	if err := verifyChecksum(path, mismatched); err == nil {
+		t.Fatal("expected mismatched checksum to fail verification")
+	}
+}